@@ -0,0 +1,88 @@
+// Package afero adapts a *fs.Broccoli bundle to github.com/spf13/afero.Fs,
+// so tools that already consume afero (Hugo, Viper, spf13 libs, test
+// harnesses) can use a Broccoli bundle as a drop-in, read-only asset source.
+package afero
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/humans-group/broccoli/fs"
+)
+
+var (
+	_ afero.Fs      = (*brocAferoFs)(nil)
+	_ afero.Lstater = (*brocAferoFs)(nil)
+)
+
+// brocAferoFs implements the read-only surface of afero.Fs on top of a
+// *fs.Broccoli; every write operation fails with syscall.EROFS.
+type brocAferoFs struct {
+	br *fs.Broccoli
+}
+
+// NewAferoFs wraps br as a read-only afero.Fs.
+func NewAferoFs(br *fs.Broccoli) afero.Fs {
+	return &brocAferoFs{br: br}
+}
+
+// NewDevOverlay layers overlay (typically afero.NewMemMapFs(), or an
+// OS-backed afero.Fs rooted at a scratch directory) on top of br via
+// afero.NewCopyOnWriteFs, so individual files can be overridden during
+// local development without rebuilding the bundle.
+func NewDevOverlay(br *fs.Broccoli, overlay afero.Fs) afero.Fs {
+	return afero.NewCopyOnWriteFs(NewAferoFs(br), overlay)
+}
+
+func (a *brocAferoFs) Name() string {
+	return "BroccoliFs"
+}
+
+// Open opens name read-only; it is equivalent to OpenFile(name, os.O_RDONLY, 0).
+func (a *brocAferoFs) Open(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile rejects any flag that implies writing with syscall.EROFS, since
+// Broccoli bundles are immutable once built.
+func (a *brocAferoFs) OpenFile(name string, flag int, _ os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_APPEND|os.O_TRUNC) != 0 {
+		return nil, syscall.EROFS
+	}
+
+	f, err := a.br.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, ok := f.(*fs.File)
+	if !ok {
+		return nil, os.ErrInvalid
+	}
+
+	return &aferoFile{f: bf, name: name}, nil
+}
+
+func (a *brocAferoFs) Stat(name string) (os.FileInfo, error) {
+	return a.br.Stat(name)
+}
+
+// LstatIfPossible satisfies afero.Lstater. Broccoli bundles have no
+// symlinks, so it always falls back to a plain Stat.
+func (a *brocAferoFs) LstatIfPossible(name string) (os.FileInfo, bool, error) {
+	info, err := a.Stat(name)
+	return info, false, err
+}
+
+func (a *brocAferoFs) Create(string) (afero.File, error)          { return nil, syscall.EROFS }
+func (a *brocAferoFs) Mkdir(string, os.FileMode) error            { return syscall.EROFS }
+func (a *brocAferoFs) MkdirAll(string, os.FileMode) error         { return syscall.EROFS }
+func (a *brocAferoFs) Remove(string) error                        { return syscall.EROFS }
+func (a *brocAferoFs) RemoveAll(string) error                     { return syscall.EROFS }
+func (a *brocAferoFs) Rename(string, string) error                { return syscall.EROFS }
+func (a *brocAferoFs) Chmod(string, os.FileMode) error            { return syscall.EROFS }
+func (a *brocAferoFs) Chtimes(string, time.Time, time.Time) error { return syscall.EROFS }
+func (a *brocAferoFs) Chown(string, int, int) error               { return syscall.EROFS }
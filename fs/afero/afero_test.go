@@ -0,0 +1,91 @@
+package afero
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	spfafero "github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/humans-group/broccoli/fs"
+)
+
+func mustNewFile(t *testing.T, name, content string) *fs.File {
+	t.Helper()
+
+	path := t.TempDir() + string(os.PathSeparator) + name
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	f, err := fs.NewFile(path)
+	assert.NoError(t, err)
+	f.Fpath = name
+
+	return f
+}
+
+func newTestBroccoli(t *testing.T) *fs.Broccoli {
+	bundle, err := fs.Pack([]*fs.File{mustNewFile(t, "hello.txt", "hello from broccoli")}, 5)
+	assert.NoError(t, err)
+	return fs.New(fs.DecompressEager, bundle)
+}
+
+func TestAferoFsReadOnly(t *testing.T) {
+	afs := NewAferoFs(newTestBroccoli(t))
+
+	f, err := afs.Open("hello.txt")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from broccoli", string(data))
+
+	info, err := afs.Stat("hello.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("hello from broccoli")), info.Size())
+
+	_, err = afs.Create("new.txt")
+	assert.Equal(t, syscall.EROFS, err)
+
+	_, err = f.Write([]byte("nope"))
+	assert.Equal(t, syscall.EROFS, err)
+}
+
+func TestAferoFileReadAtLeavesCursorUntouched(t *testing.T) {
+	afs := NewAferoFs(newTestBroccoli(t))
+
+	f, err := afs.Open("hello.txt")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	// Advance the cursor past where ReadAt will read, so a ReadAt that
+	// mistakenly moves it would be caught by the sequential Read below.
+	head := make([]byte, 10)
+	n, err := f.Read(head)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	at := make([]byte, 5)
+	n, err = f.ReadAt(at, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", string(at))
+
+	// ReadAt must not disturb f's own cursor, which Read left at 10.
+	rest, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.Equal(t, " broccoli", string(rest))
+}
+
+func TestAferoDevOverlay(t *testing.T) {
+	overlay := spfafero.NewMemMapFs()
+	assert.NoError(t, spfafero.WriteFile(overlay, "hello.txt", []byte("overridden"), 0644))
+
+	afs := NewDevOverlay(newTestBroccoli(t), overlay)
+
+	data, err := spfafero.ReadFile(afs, "hello.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "overridden", string(data))
+}
@@ -0,0 +1,101 @@
+package afero
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/spf13/afero"
+
+	"github.com/humans-group/broccoli/fs"
+)
+
+var _ afero.File = (*aferoFile)(nil)
+
+// aferoFile adapts a *fs.File to afero.File, a superset of http.File that
+// additionally requires Write/WriteAt/WriteString/Truncate/Sync. The four
+// writing methods fail with syscall.EROFS since bundles are read-only;
+// Sync has nothing to flush and returns nil.
+type aferoFile struct {
+	f    *fs.File
+	name string
+}
+
+func (af *aferoFile) Name() string {
+	return filepath.Base(af.name)
+}
+
+func (af *aferoFile) Read(p []byte) (int, error) {
+	return af.f.Read(p)
+}
+
+// ReadAt fills p starting at off, without disturbing af's own Read/Seek
+// cursor: it saves the current position, seeks to off, reads p full (per
+// the io.ReaderAt contract), then restores the saved position.
+func (af *aferoFile) ReadAt(p []byte, off int64) (int, error) {
+	cur, err := af.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	defer af.f.Seek(cur, io.SeekStart)
+
+	if _, err := af.f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(af.f, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (af *aferoFile) Seek(offset int64, whence int) (int64, error) {
+	return af.f.Seek(offset, whence)
+}
+
+func (af *aferoFile) Close() error {
+	return af.f.Close()
+}
+
+func (af *aferoFile) Readdir(count int) ([]os.FileInfo, error) {
+	return af.f.Readdir(count)
+}
+
+func (af *aferoFile) Readdirnames(n int) ([]string, error) {
+	infos, err := af.f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
+
+func (af *aferoFile) Stat() (os.FileInfo, error) {
+	return af.f.Stat()
+}
+
+func (af *aferoFile) Sync() error {
+	return nil
+}
+
+func (af *aferoFile) Truncate(int64) error {
+	return syscall.EROFS
+}
+
+func (af *aferoFile) Write([]byte) (int, error) {
+	return 0, syscall.EROFS
+}
+
+func (af *aferoFile) WriteAt([]byte, int64) (int, error) {
+	return 0, syscall.EROFS
+}
+
+func (af *aferoFile) WriteString(string) (int, error) {
+	return 0, syscall.EROFS
+}
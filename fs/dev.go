@@ -0,0 +1,116 @@
+package fs
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DevBroccoli serves a directory straight off disk, the way dev-mode
+// (pre-bundle) serving has always worked, except that each file's
+// brotli-compressed bytes are cached under CacheDir (see DevCache) rather
+// than recomputed on every request, giving near-production response
+// latency while keeping live-reload semantics.
+type DevBroccoli struct {
+	root    string
+	cache   *DevCache
+	quality int
+}
+
+// NewDevBroccoli serves files under root, caching compressed copies under
+// cacheDir at the given brotli quality.
+func NewDevBroccoli(root, cacheDir string, quality int) (*DevBroccoli, error) {
+	cache, err := OpenDevCache(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DevBroccoli{root: root, cache: cache, quality: quality}, nil
+}
+
+// Cache returns the underlying DevCache, so callers can Prune or Clear it.
+func (d *DevBroccoli) Cache() *DevCache {
+	return d.cache
+}
+
+// Open serves name relative to root, decompressing the cached block (or
+// building it, if this is the first request since the file last changed).
+func (d *DevBroccoli) Open(name string) (http.File, error) {
+	name = clean(name)
+	full := filepath.Join(d.root, filepath.FromSlash(name))
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+
+	if info.IsDir() {
+		return &File{Fpath: name, Fmode: os.ModeDir, Modtime: info.ModTime(), dir: true}, nil
+	}
+
+	compressed, err := d.cache.Compressed(full, d.quality)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(brotli.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Fpath:   name,
+		Data:    data,
+		Fmode:   info.Mode(),
+		Modtime: info.ModTime(),
+		size:    int64(len(data)),
+	}, nil
+}
+
+// Serve returns an http.Handler serving root the same way Broccoli.Serve
+// would for a bundled tree, backed by the on-disk dev cache instead.
+func (d *DevBroccoli) Serve(root string) http.Handler {
+	root = clean(root)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Join(root, clean(r.URL.Path))
+
+		f, err := d.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if info.IsDir() {
+			index, err := d.Open(path.Join(name, "index.html"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			defer index.Close()
+
+			indexInfo, err := index.Stat()
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			http.ServeContent(w, r, indexInfo.Name(), indexInfo.ModTime(), index)
+			return
+		}
+
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
@@ -0,0 +1,86 @@
+package fs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevCacheReusesUnchangedFile(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	path := filepath.Join(srcDir, "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	cache, err := OpenDevCache(cacheDir)
+	assert.NoError(t, err)
+
+	first, err := cache.Compressed(path, 5)
+	assert.NoError(t, err)
+
+	second, err := cache.Compressed(path, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// Changing the content (and so the mtime) must invalidate the cache.
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(path, []byte("hello, world"), 0644))
+
+	third, err := cache.Compressed(path, 5)
+	assert.NoError(t, err)
+	assert.NotEqual(t, second, third)
+}
+
+func TestDevCachePruneAndClear(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	path := filepath.Join(srcDir, "a.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	cache, err := OpenDevCache(cacheDir)
+	assert.NoError(t, err)
+
+	_, err = cache.Compressed(path, 5)
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.Prune(time.Hour))
+	assert.Len(t, cache.entries, 1)
+
+	assert.NoError(t, cache.Prune(0))
+	assert.Len(t, cache.entries, 0)
+
+	_, err = cache.Compressed(path, 5)
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Clear())
+	assert.Len(t, cache.entries, 0)
+}
+
+func TestDevBroccoliServe(t *testing.T) {
+	srcDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "index.html"), []byte("<h1>hi</h1>"), 0644))
+
+	dev, err := NewDevBroccoli(srcDir, cacheDir, 5)
+	assert.NoError(t, err)
+
+	srv := httptest.NewServer(dev.Serve(""))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/index.html")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "<h1>hi</h1>", string(body))
+}
@@ -0,0 +1,164 @@
+package fs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// devCacheIndexFile is the JSON sidecar mapping a source path to the hash,
+// mtime and size it was cached under, so a later lookup can tell whether
+// the file changed without re-reading it.
+const devCacheIndexFile = "index.json"
+
+type devCacheEntry struct {
+	Hash     string    `json:"hash"`
+	ModTime  time.Time `json:"mtime"`
+	Size     int64     `json:"size"`
+	Accessed time.Time `json:"accessed"`
+}
+
+// DevCache is an on-disk, content-addressed cache of brotli-compressed
+// files, keyed by a source path's mtime and size. It lets dev-mode serving
+// (DevBroccoli) skip recompressing a file that hasn't changed since it was
+// last cached, inspired by Hugo's filecache. Pack and PackStream do not use
+// it; they compress every file they are given.
+type DevCache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string]devCacheEntry
+}
+
+// OpenDevCache opens (creating if necessary) a DevCache rooted at dir.
+func OpenDevCache(dir string) (*DevCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &DevCache{dir: dir, entries: map[string]devCacheEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(dir, devCacheIndexFile))
+	switch {
+	case os.IsNotExist(err):
+		return c, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *DevCache) blockPath(hash string) string {
+	return filepath.Join(c.dir, hash+".br")
+}
+
+func (c *DevCache) saveIndex() error {
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.dir, devCacheIndexFile), data, 0644)
+}
+
+// Compressed returns the brotli-compressed bytes for the file at path,
+// reusing the previously cached block if path's mtime and size still match
+// what was cached, and recompressing (at quality) otherwise.
+func (c *DevCache) Compressed(path string, quality int) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := filepath.ToSlash(path)
+	if entry, ok := c.entries[key]; ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+		if block, err := os.ReadFile(c.blockPath(entry.Hash)); err == nil {
+			entry.Accessed = time.Now()
+			c.entries[key] = entry
+			c.saveIndex()
+			return block, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var buf bytes.Buffer
+	w := brotli.NewWriterLevel(&buf, quality)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(c.blockPath(hash), buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+
+	c.entries[key] = devCacheEntry{Hash: hash, ModTime: info.ModTime(), Size: info.Size(), Accessed: time.Now()}
+	if err := c.saveIndex(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// HasHash reports whether a block with the given content hash is already
+// present in the cache. Nothing in this package calls it; in particular,
+// Pack and PackStream do not consult it before compressing a file.
+func (c *DevCache) HasHash(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, err := os.Stat(c.blockPath(hash))
+	return err == nil
+}
+
+// Prune removes cached blocks that haven't been read within maxAge.
+func (c *DevCache) Prune(maxAge time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cutoff := time.Now().Add(-maxAge)
+	for key, entry := range c.entries {
+		if entry.Accessed.Before(cutoff) {
+			os.Remove(c.blockPath(entry.Hash))
+			delete(c.entries, key)
+		}
+	}
+
+	return c.saveIndex()
+}
+
+// Clear empties the cache, removing every compressed block and the index.
+func (c *DevCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		os.Remove(c.blockPath(entry.Hash))
+	}
+	c.entries = map[string]devCacheEntry{}
+
+	return c.saveIndex()
+}
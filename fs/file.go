@@ -0,0 +1,333 @@
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ iofs.ReadDirFile = (*File)(nil)
+
+// File represents a single file or directory entry inside a Broccoli bundle.
+// It implements http.File so that a Broccoli can be served directly via
+// http.FileServer (or Broccoli.Serve), and fs.ReadDirFile so that directory
+// handles can be read through io/fs as well.
+type File struct {
+	Fpath   string
+	Data    []byte
+	Fmode   os.FileMode
+	Modtime time.Time
+
+	dir    bool
+	pos    int64
+	closed bool
+	size   int64 // raw (uncompressed) size; known up front, even before decode
+
+	// compressed reports whether the file's content is a compressed block in
+	// br.payload that needs to be inflated before it can be read. It is
+	// always false for files loaded from the legacy whole-bundle format, and
+	// for eagerly decompressed block-format files.
+	compressed bool
+	br         *Broccoli
+
+	// Block-format fields: where this file's compressed bytes live in
+	// br.payload, and how they're encoded. Unused by the legacy format.
+	blockOffset   int64
+	blockCompSize int64
+	algo          compressionAlgo
+
+	// cache holds lazily-decoded bytes and is shared across clones of this
+	// File, so every handle opened after the first benefits from it. It is
+	// nil for legacy-format files, whose Data is already populated; under
+	// DecompressEager it is filled when the bundle loads, and Data holds the
+	// same decoded bytes.
+	cache *fileCache
+}
+
+// fileCache holds a file's decoded bytes, shared by every handle cloned
+// from the same bundle entry.
+type fileCache struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (fc *fileCache) clear() {
+	fc.mu.Lock()
+	fc.data = nil
+	fc.mu.Unlock()
+}
+
+// NewFile reads a file (or records a directory) from disk for packing.
+//
+// This function is only supposed to be called by broccoli the tool.
+func NewFile(fpath string) (*File, error) {
+	info, err := os.Stat(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		Fpath:   filepath.ToSlash(fpath),
+		Fmode:   info.Mode(),
+		Modtime: info.ModTime().Truncate(time.Second),
+		dir:     info.IsDir(),
+	}
+
+	if !f.dir {
+		data, err := os.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		f.Data = data
+		f.size = int64(len(data))
+	}
+
+	return f, nil
+}
+
+// bytes returns the file's decoded content, decompressing its block on
+// first access if the File was loaded lazily.
+func (f *File) bytes() ([]byte, error) {
+	if f.cache == nil {
+		return f.Data, nil
+	}
+	return f.decode()
+}
+
+// decode inflates the file's compressed block, caching the result so later
+// handles (including ones already open) see it without re-inflating.
+//
+// f.cache.mu is released before touch/add are called: those can run another
+// file's eviction callback (fileCache.clear, locking that file's cache.mu),
+// and holding our own cache.mu across that call would let two concurrent
+// decodes under LRU pressure, evicting each other, lock the two fileCache
+// mutexes in opposite order.
+func (f *File) decode() ([]byte, error) {
+	f.cache.mu.Lock()
+
+	if data := f.cache.data; data != nil {
+		f.cache.mu.Unlock()
+		if f.br != nil && f.br.lru != nil {
+			f.br.lru.touch(f.Fpath)
+		}
+		return data, nil
+	}
+
+	raw, err := f.br.readBlock(f.blockOffset, f.blockCompSize, f.algo)
+	if err != nil {
+		f.cache.mu.Unlock()
+		return nil, err
+	}
+
+	f.cache.data = raw
+	cache := f.cache
+	f.cache.mu.Unlock()
+
+	if f.br != nil && f.br.lru != nil {
+		f.br.lru.add(f.Fpath, func() { cache.clear() })
+	}
+
+	return raw, nil
+}
+
+// clone returns an independent handle onto the same file data, with its own
+// read cursor, suitable for returning from Broccoli.Open.
+func (f *File) clone() *File {
+	cp := *f
+	cp.pos = 0
+	cp.closed = false
+	return &cp
+}
+
+// Open reopens the file, resetting its read cursor. It is a no-op for
+// freshly created handles.
+func (f *File) Open() error {
+	f.pos = 0
+	f.closed = false
+	return nil
+}
+
+// Read implements io.Reader.
+func (f *File) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	data, err := f.bytes()
+	if err != nil {
+		return 0, err
+	}
+
+	if f.pos >= int64(len(data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, data[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+// Seek implements io.Seeker. It never triggers decompression: the file's
+// raw size is always known from the bundle index (or, failing that, from
+// len(Data); see effectiveSize).
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+
+	size := f.effectiveSize()
+
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.pos + offset
+	case io.SeekEnd:
+		abs = size + offset
+	default:
+		return 0, errSeekWhence
+	}
+
+	if abs < 0 || abs > size {
+		return 0, errSeekOffset
+	}
+
+	f.pos = abs
+	return abs, nil
+}
+
+// effectiveSize returns the file's raw size. size is normally set up front
+// by NewFile/New/dev.go, but a *File built directly as a struct literal (as
+// tests do) can leave it at its zero value even though Data is populated;
+// fall back to len(Data) so such handles aren't silently treated as empty,
+// both here and in fileInfo.Size.
+func (f *File) effectiveSize() int64 {
+	if f.size == 0 && !f.dir && len(f.Data) > 0 {
+		return int64(len(f.Data))
+	}
+	return f.size
+}
+
+// Close implements io.Closer. Closing an already-closed file returns
+// os.ErrClosed, matching os.File.
+func (f *File) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+// Readdir reads the directory's contents in lexical order. A count > 0
+// returns up to count entries, continuing from the previous such call, and
+// io.EOF once none remain. A count <= 0 returns every entry of the
+// directory and leaves that position unchanged.
+func (f *File) Readdir(count int) ([]os.FileInfo, error) {
+	if f.closed {
+		return nil, os.ErrInvalid
+	}
+	if !f.dir || f.br == nil {
+		return nil, os.ErrInvalid
+	}
+
+	children := f.br.children(f.Fpath)
+	if count > 0 && f.pos >= int64(len(children)) {
+		return nil, io.EOF
+	}
+
+	start := int(f.pos)
+	end := len(children)
+	if count <= 0 {
+		start = 0
+	} else if start+count < end {
+		end = start + count
+	}
+
+	infos := make([]os.FileInfo, 0, end-start)
+	for _, child := range children[start:end] {
+		info, err := child.Stat()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	if count > 0 {
+		f.pos = int64(end)
+	}
+	return infos, nil
+}
+
+// Stat returns the os.FileInfo describing the file.
+func (f *File) Stat() (os.FileInfo, error) {
+	return &fileInfo{f}, nil
+}
+
+// ReadDir implements fs.ReadDirFile, so directory handles opened through
+// rootedFS (and so *Broccoli via http.FS) satisfy io/fs's directory-reading
+// contract, e.g. for fstest.TestFS. Unlike Readdir, an n <= 0 returns only
+// the entries not yet read, and consumes them.
+func (f *File) ReadDir(n int) ([]iofs.DirEntry, error) {
+	if n <= 0 && !f.closed && f.dir && f.br != nil {
+		n = len(f.br.children(f.Fpath)) - int(f.pos)
+		if n <= 0 {
+			return []iofs.DirEntry{}, nil
+		}
+	}
+
+	infos, err := f.Readdir(n)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]iofs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = iofs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+var (
+	errSeekWhence = seekError("Seek: bad whence")
+	errSeekOffset = seekError("Seek: bad offset")
+)
+
+type seekError string
+
+func (e seekError) Error() string { return string(e) }
+
+// fileInfo adapts *File to os.FileInfo.
+type fileInfo struct {
+	f *File
+}
+
+func (fi *fileInfo) Name() string {
+	return filepath.Base(fi.f.Fpath)
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.f.effectiveSize()
+}
+
+func (fi *fileInfo) Mode() os.FileMode {
+	if fi.f.dir {
+		return os.ModeDir
+	}
+	return os.FileMode(0444)
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.f.Modtime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.f.dir
+}
+
+func (fi *fileInfo) Sys() interface{} {
+	return nil
+}
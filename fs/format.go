@@ -0,0 +1,172 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// formatMagic tags a bundle as using the random-access, block-based
+// format. Bundles without this prefix are assumed to be the original
+// gob+brotli whole-bundle format and are loaded via the legacy path in
+// pack.go.
+var formatMagic = [4]byte{'B', 'R', 'C', 'C'}
+
+const (
+	// formatVersionIndexFirst is Pack's original block-format layout:
+	// magic, version, index, then payload. Still readable for bundles
+	// written before PackStream existed.
+	formatVersionIndexFirst = 2
+	// formatVersionIndexLast is PackStream's layout: magic, version,
+	// payload, then index, then an 8-byte index length footer. Writing the
+	// index last is what lets PackStream stream payload blocks straight to
+	// the output as they're compressed, without ever buffering the whole
+	// bundle in memory; only the index entries are kept until the end.
+	formatVersionIndexLast = 3
+)
+
+// compressionAlgo identifies how a single payload block is encoded.
+type compressionAlgo byte
+
+const (
+	// algoStore marks a block that is stored as-is, uncompressed (used for
+	// directories and for files brotli couldn't shrink).
+	algoStore compressionAlgo = iota
+	// algoBrotli marks a block compressed independently with brotli.
+	algoBrotli
+)
+
+// indexEntry describes one file's metadata and its block's location within
+// a bundle's payload region.
+type indexEntry struct {
+	Path           string
+	Mode           os.FileMode
+	ModTime        time.Time
+	RawSize        int64
+	CompressedSize int64
+	Offset         int64
+	Algo           compressionAlgo
+}
+
+// isBlockFormat reports whether bundle starts with the block-format magic,
+// regardless of which version follows it.
+func isBlockFormat(bundle []byte) bool {
+	return len(bundle) >= len(formatMagic)+1 && bytes.Equal(bundle[:len(formatMagic)], formatMagic[:])
+}
+
+var errShortBundle = errors.New("broccoli: truncated bundle header")
+
+// encodeIndexBytes gob-encodes and brotli-compresses entries for storage in
+// a bundle.
+func encodeIndexBytes(entries []indexEntry, quality int) ([]byte, error) {
+	var b bytes.Buffer
+	w := brotli.NewWriterLevel(&b, quality)
+	if err := gob.NewEncoder(w).Encode(entries); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decodeIndexBytes(data []byte) ([]indexEntry, error) {
+	var entries []indexEntry
+	r := brotli.NewReader(bytes.NewReader(data))
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// encodeBundleIndexFirst assembles a complete formatVersionIndexFirst
+// bundle: magic + version, the encoded index, then the payload blocks the
+// caller already wrote to payload.
+func encodeBundleIndexFirst(entries []indexEntry, payload []byte, quality int) ([]byte, error) {
+	indexBytes, err := encodeIndexBytes(entries, quality)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(formatMagic[:])
+	out.WriteByte(formatVersionIndexFirst)
+
+	var lenBuf [8]byte
+	binary.BigEndian.PutUint64(lenBuf[:], uint64(len(indexBytes)))
+	out.Write(lenBuf[:])
+
+	out.Write(indexBytes)
+	out.Write(payload)
+
+	return out.Bytes(), nil
+}
+
+// decodeBundle splits a block-format bundle into its index entries and its
+// payload region (still compressed, block by block), dispatching on the
+// version byte that follows the magic.
+func decodeBundle(bundle []byte) (entries []indexEntry, payload []byte, err error) {
+	if !isBlockFormat(bundle) {
+		return nil, nil, errShortBundle
+	}
+
+	switch bundle[len(formatMagic)] {
+	case formatVersionIndexFirst:
+		return decodeBundleIndexFirst(bundle)
+	case formatVersionIndexLast:
+		return decodeBundleIndexLast(bundle)
+	default:
+		return nil, nil, fmt.Errorf("broccoli: unsupported bundle version %d", bundle[len(formatMagic)])
+	}
+}
+
+func decodeBundleIndexFirst(bundle []byte) (entries []indexEntry, payload []byte, err error) {
+	start := len(formatMagic) + 1
+	if len(bundle) < start+8 {
+		return nil, nil, errShortBundle
+	}
+
+	indexLen := binary.BigEndian.Uint64(bundle[start : start+8])
+	start += 8
+	end := start + int(indexLen)
+	if end > len(bundle) {
+		return nil, nil, errShortBundle
+	}
+
+	entries, err = decodeIndexBytes(bundle[start:end])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, bundle[end:], nil
+}
+
+// decodeBundleIndexLast reads a PackStream bundle: payload starts right
+// after the header, and the index sits in a footer at the very end,
+// sized by the last 8 bytes.
+func decodeBundleIndexLast(bundle []byte) (entries []indexEntry, payload []byte, err error) {
+	headerLen := len(formatMagic) + 1
+	if len(bundle) < headerLen+8 {
+		return nil, nil, errShortBundle
+	}
+
+	n := len(bundle)
+	indexLen := binary.BigEndian.Uint64(bundle[n-8:])
+	indexStart := n - 8 - int(indexLen)
+	if indexStart < headerLen {
+		return nil, nil, errShortBundle
+	}
+
+	entries, err = decodeIndexBytes(bundle[indexStart : n-8])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, bundle[headerLen:indexStart], nil
+}
@@ -0,0 +1,180 @@
+package fs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Broccoli is a virtual, read-only file system whose contents are bundled
+// at build time (see Pack) and reconstructed by New.
+type Broccoli struct {
+	filePaths []string
+	files     map[string]*File
+
+	// payload backs every File's compressed block when the bundle uses the
+	// random-access block format; nil for bundles loaded via the legacy
+	// whole-bundle format, where every File already holds its plain Data.
+	payload []byte
+	policy  DecompressPolicy
+	lru     *lruCache
+}
+
+// readBlock inflates (or copies, for stored blocks) the compressed bytes
+// for one file out of the bundle's payload region.
+func (br *Broccoli) readBlock(offset, compSize int64, algo compressionAlgo) ([]byte, error) {
+	if offset < 0 || compSize < 0 || offset+compSize > int64(len(br.payload)) {
+		return nil, errors.New("broccoli: block out of range")
+	}
+
+	block := br.payload[offset : offset+compSize]
+
+	switch algo {
+	case algoStore:
+		raw := make([]byte, len(block))
+		copy(raw, block)
+		return raw, nil
+	case algoBrotli:
+		return io.ReadAll(brotli.NewReader(bytes.NewReader(block)))
+	default:
+		return nil, fmt.Errorf("broccoli: unknown compression algo %d", algo)
+	}
+}
+
+// RawReader returns a reader over path's on-disk representation, along with
+// the Content-Encoding it is stored with. An encoding of "br" means the
+// bytes are still brotli-compressed and can be streamed straight through to
+// a client that advertised "Accept-Encoding: br"; an empty encoding means
+// the bytes are already plain.
+func (br *Broccoli) RawReader(path string) (io.ReadCloser, string, error) {
+	path = clean(path)
+
+	f, ok := br.files[path]
+	if !ok {
+		return nil, "", os.ErrNotExist
+	}
+	if f.dir {
+		return nil, "", os.ErrInvalid
+	}
+
+	if f.cache != nil && f.algo == algoBrotli {
+		block := br.payload[f.blockOffset : f.blockOffset+f.blockCompSize]
+		return io.NopCloser(bytes.NewReader(block)), "br", nil
+	}
+
+	data, err := f.bytes()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), "", nil
+}
+
+// clean normalizes a virtual path the way the bundle stores it: forward
+// slashes, no leading "./" and no trailing slash.
+func clean(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	return strings.Trim(name, "/")
+}
+
+// Open opens the named file for reading. The returned http.File can be
+// passed straight to http.FileServer, or used via Broccoli.Serve.
+func (br *Broccoli) Open(name string) (http.File, error) {
+	name = clean(name)
+
+	f, ok := br.files[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return f.clone(), nil
+}
+
+// Stat returns the os.FileInfo describing the named file.
+func (br *Broccoli) Stat(name string) (os.FileInfo, error) {
+	f, err := br.Open(name)
+	if err != nil {
+		return nil, os.ErrNotExist
+	}
+	defer f.Close()
+
+	return f.Stat()
+}
+
+// Walk walks the virtual file tree rooted at root, calling walkFn for every
+// file and directory in lexical order, mirroring filepath.Walk.
+func (br *Broccoli) Walk(root string, walkFn func(path string, info os.FileInfo, err error) error) error {
+	root = clean(root)
+
+	paths := make([]string, len(br.filePaths))
+	copy(paths, br.filePaths)
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if root != "" && p != root && !strings.HasPrefix(p, root+"/") {
+			continue
+		}
+
+		f := br.files[p]
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		if err := walkFn(p, info, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// children returns the immediate children of dir, in lexical order.
+func (br *Broccoli) children(dir string) []*File {
+	dir = clean(dir)
+
+	prefix := dir + "/"
+	if dir == "" {
+		prefix = ""
+	}
+
+	seen := map[string]bool{}
+	var children []string
+
+	for _, p := range br.filePaths {
+		if !strings.HasPrefix(p, prefix) || p == dir {
+			continue
+		}
+
+		rest := strings.TrimPrefix(p, prefix)
+		name := rest
+		if idx := strings.IndexByte(rest, '/'); idx >= 0 {
+			name = rest[:idx]
+		}
+
+		childPath := path.Join(dir, name)
+		if !seen[childPath] {
+			seen[childPath] = true
+			children = append(children, childPath)
+		}
+	}
+
+	sort.Strings(children)
+
+	files := make([]*File, 0, len(children))
+	for _, c := range children {
+		if f, ok := br.files[c]; ok {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
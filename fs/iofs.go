@@ -0,0 +1,153 @@
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"path"
+	"sort"
+)
+
+// rootedFS is the io/fs.FS view returned by Broccoli.FS. All paths are
+// resolved relative to prefix, so Sub-ing a rootedFS just narrows prefix.
+type rootedFS struct {
+	br     *Broccoli
+	prefix string
+}
+
+var (
+	_ iofs.FS         = rootedFS{}
+	_ iofs.ReadDirFS  = rootedFS{}
+	_ iofs.StatFS     = rootedFS{}
+	_ iofs.ReadFileFS = rootedFS{}
+	_ iofs.GlobFS     = rootedFS{}
+	_ iofs.SubFS      = rootedFS{}
+)
+
+// FS returns an io/fs.FS view of br, rooted at the bundle's top level. The
+// result satisfies fs.FS, fs.ReadDirFS, fs.StatFS, fs.ReadFileFS, fs.GlobFS
+// and fs.SubFS, with paths following fs.ValidPath rules.
+func (br *Broccoli) FS() iofs.FS {
+	return rootedFS{br: br}
+}
+
+// resolve validates name against fs.ValidPath and joins it onto the
+// filesystem's prefix, returning the bundle-relative path to look up.
+func (r rootedFS) resolve(op, name string) (string, error) {
+	if !iofs.ValidPath(name) {
+		return "", &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+	if name == "." {
+		return clean(r.prefix), nil
+	}
+	return clean(path.Join(r.prefix, name)), nil
+}
+
+// Open implements fs.FS.
+func (r rootedFS) Open(name string) (iofs.File, error) {
+	full, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := r.br.files[full]; ok {
+		return f.clone(), nil
+	}
+
+	// No explicit entry for this path: if it's a prefix of other entries,
+	// treat it as an (implied) directory; otherwise it doesn't exist.
+	if len(r.br.children(full)) > 0 || full == "" {
+		return &File{Fpath: full, Fmode: iofs.ModeDir, dir: true, br: r.br}, nil
+	}
+
+	return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (r rootedFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	df, ok := f.(*File)
+	if !ok || !df.dir {
+		return nil, &iofs.PathError{Op: "readdir", Path: name, Err: iofs.ErrInvalid}
+	}
+
+	infos, err := df.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+
+	entries := make([]iofs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = iofs.FileInfoToDirEntry(info)
+	}
+	return entries, nil
+}
+
+// Stat implements fs.StatFS.
+func (r rootedFS) Stat(name string) (iofs.FileInfo, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.Stat()
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (r rootedFS) ReadFile(name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// Glob implements fs.GlobFS.
+func (r rootedFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err := iofs.WalkDir(r, ".", func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Sub implements fs.SubFS.
+func (r rootedFS) Sub(dir string) (iofs.FS, error) {
+	if dir == "." {
+		return r, nil
+	}
+
+	full, err := r.resolve("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return rootedFS{br: r.br, prefix: full}, nil
+}
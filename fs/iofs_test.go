@@ -0,0 +1,59 @@
+package fs
+
+import (
+	iofs "io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newIofsTestBroccoli() *Broccoli {
+	files := map[string]*File{
+		"a.txt":        {Fpath: "a.txt", Data: []byte("hello"), Fmode: 0444, Modtime: time.Unix(1, 0)},
+		"dir":          {Fpath: "dir", Fmode: iofs.ModeDir, Modtime: time.Unix(1, 0), dir: true},
+		"dir/b.txt":    {Fpath: "dir/b.txt", Data: []byte("world"), Fmode: 0444, Modtime: time.Unix(1, 0)},
+		"dir/sub":      {Fpath: "dir/sub", Fmode: iofs.ModeDir, Modtime: time.Unix(1, 0), dir: true},
+		"dir/sub/c.go": {Fpath: "dir/sub/c.go", Data: []byte("package sub"), Fmode: 0444, Modtime: time.Unix(1, 0)},
+	}
+
+	br := &Broccoli{files: files}
+	for p, f := range files {
+		f.br = br
+		br.filePaths = append(br.filePaths, p)
+	}
+
+	return br
+}
+
+func TestBroccoliFS(t *testing.T) {
+	br := newIofsTestBroccoli()
+
+	err := fstest.TestFS(br.FS(), "a.txt", "dir/b.txt", "dir/sub/c.go")
+	assert.NoError(t, err)
+}
+
+func TestBroccoliFSReadFileAndGlob(t *testing.T) {
+	br := newIofsTestBroccoli()
+	fsys := br.FS()
+
+	data, err := iofs.ReadFile(fsys, "dir/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+
+	matches, err := iofs.Glob(fsys, "dir/*.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dir/b.txt"}, matches)
+
+	entries, err := iofs.ReadDir(fsys, "dir")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	sub, err := iofs.Sub(fsys, "dir")
+	assert.NoError(t, err)
+
+	data, err = iofs.ReadFile(sub, "b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(data))
+}
@@ -0,0 +1,80 @@
+package fs
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultLRUCapacity bounds how many decoded files DecompressLRU keeps
+// resident by default.
+const defaultLRUCapacity = 128
+
+// lruCache tracks the set of recently-decoded files under DecompressLRU,
+// evicting the least recently used entry once capacity is exceeded.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	evict func()
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// touch marks key as most-recently-used.
+func (c *lruCache) touch(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+	}
+}
+
+// add records key as the most-recently-used entry, calling evict on
+// whichever entry falls off the back once capacity is exceeded.
+//
+// Eviction callbacks run after c.mu is released, so c.mu is never held while
+// evict (in practice, fileCache.clear) acquires another file's cache lock.
+// File.decode likewise releases its own cache lock before calling add/touch,
+// so the two kinds of mutex are never nested in either order.
+func (c *lruCache) add(key string, evict func()) {
+	var evicted []func()
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		c.mu.Unlock()
+		return
+	}
+
+	el := c.ll.PushFront(&lruEntry{key: key, evict: evict})
+	c.items[key] = el
+
+	for c.ll.Len() > c.capacity {
+		back := c.ll.Back()
+		if back == nil {
+			break
+		}
+
+		c.ll.Remove(back)
+		entry := back.Value.(*lruEntry)
+		delete(c.items, entry.key)
+		evicted = append(evicted, entry.evict)
+	}
+	c.mu.Unlock()
+
+	for _, evict := range evicted {
+		evict()
+	}
+}
@@ -2,13 +2,40 @@ package fs
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"sort"
 
 	"github.com/andybalholm/brotli"
 )
 
-// Pack compresses a set of files from disk for bundled use in the generated code.
+// DecompressPolicy controls when a block-format bundle's files are
+// inflated into memory.
+type DecompressPolicy int
+
+const (
+	// DecompressEager inflates every file as soon as the bundle is loaded,
+	// trading startup time and memory for zero-latency first reads.
+	DecompressEager DecompressPolicy = iota
+	// DecompressLazy inflates a file's block the first time it's opened,
+	// and keeps the decoded bytes cached afterwards.
+	DecompressLazy
+	// DecompressLRU behaves like DecompressLazy, but bounds the number of
+	// decoded files kept resident, evicting the least recently used one
+	// once the bound (defaultLRUCapacity) is exceeded.
+	DecompressLRU
+)
+
+// Pack compresses a set of files from disk for bundled use in the generated
+// code. Each file is brotli-compressed independently, so callers can seek
+// straight to (and lazily inflate) any single file without touching the
+// rest of the bundle; see New and DecompressPolicy.
+//
+// Pack is a thin, in-memory wrapper around PackStream: it feeds files into
+// a channel and buffers PackStream's output, so callers that don't need
+// streaming or custom concurrency can keep calling it exactly as before.
+// Use PackStream directly to compress files in parallel and stream the
+// result straight to an io.Writer.
 //
 // This function is only supposed to be called by broccoli the tool.
 func Pack(files []*File, quality int) ([]byte, error) {
@@ -16,25 +43,101 @@ func Pack(files []*File, quality int) ([]byte, error) {
 		return files[i].Fpath < files[j].Fpath
 	})
 
+	ch := make(chan *File)
+	go func() {
+		defer close(ch)
+		for _, f := range files {
+			ch <- f
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := PackStream(context.Background(), ch, &buf, PackOptions{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// compressBlock brotli-compresses data on its own, so it can later be
+// inflated independently of every other file in the bundle.
+func compressBlock(data []byte, quality int) ([]byte, error) {
 	var b bytes.Buffer
 	w := brotli.NewWriterLevel(&b, quality)
-	if err := gob.NewEncoder(w).Encode(files); err != nil {
+	if _, err := w.Write(data); err != nil {
 		return nil, err
 	}
-
 	if err := w.Close(); err != nil {
 		return nil, err
 	}
-
 	return b.Bytes(), nil
 }
 
-// New decompresses the bundle byte-slice and creates a virtual file system.
-// Depending on whether if optional decompression is enabled, it will or
-// will not decompress the files while loading them.
+// New creates a virtual file system from a bundle produced by Pack.
+// Bundles using the block format are indexed rather than fully
+// decompressed; policy controls when each file's bytes are actually
+// inflated. Bundles from the original, pre-block format are still
+// supported, and always decompress eagerly, exactly as before.
 //
 // This function is only supposed to be called from the generated code.
-func New(opt bool, bundle []byte) *Broccoli {
+func New(policy DecompressPolicy, bundle []byte) *Broccoli {
+	if !isBlockFormat(bundle) {
+		return newLegacy(bundle)
+	}
+
+	entries, payload, err := decodeBundle(bundle)
+	if err != nil {
+		panic(err)
+	}
+
+	br := &Broccoli{
+		filePaths: make([]string, 0, len(entries)),
+		files:     map[string]*File{},
+		payload:   payload,
+		policy:    policy,
+	}
+
+	if policy == DecompressLRU {
+		br.lru = newLRUCache(defaultLRUCapacity)
+	}
+
+	for _, e := range entries {
+		f := &File{
+			Fpath:         e.Path,
+			Fmode:         e.Mode,
+			Modtime:       e.ModTime,
+			dir:           e.Mode.IsDir(),
+			size:          e.RawSize,
+			compressed:    e.Algo != algoStore,
+			blockOffset:   e.Offset,
+			blockCompSize: e.CompressedSize,
+			algo:          e.Algo,
+			br:            br,
+		}
+
+		if !f.dir {
+			f.cache = &fileCache{}
+		}
+
+		if policy == DecompressEager && !f.dir {
+			data, err := f.decode()
+			if err != nil {
+				panic(err)
+			}
+			f.Data = data
+			f.compressed = false
+		}
+
+		br.files[f.Fpath] = f
+		br.filePaths = append(br.filePaths, f.Fpath)
+	}
+
+	return br
+}
+
+// newLegacy decodes a bundle produced by the original gob+brotli,
+// whole-bundle format, where every file's Data is already plain.
+func newLegacy(bundle []byte) *Broccoli {
 	var files []*File
 	r := brotli.NewReader(bytes.NewBuffer(bundle))
 	if err := gob.NewDecoder(r).Decode(&files); err != nil {
@@ -51,6 +154,8 @@ func New(opt bool, bundle []byte) *Broccoli {
 		// so mark as not compressed and attach the broccoli reference.
 		f.compressed = false
 		f.br = br
+		f.dir = f.Fmode.IsDir()
+		f.size = int64(len(f.Data))
 
 		br.files[f.Fpath] = f
 		br.filePaths = append(br.filePaths, f.Fpath)
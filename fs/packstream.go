@@ -0,0 +1,222 @@
+package fs
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// PackOptions configures PackStream.
+type PackOptions struct {
+	// Quality is the brotli compression level (0-11).
+	Quality int
+	// Concurrency is how many files are compressed in parallel. Zero (the
+	// default) means runtime.GOMAXPROCS(0).
+	Concurrency int
+	// BlockFormat is accepted but ignored: PackStream always emits the
+	// block format, since that's what makes lazy and random-access reads
+	// possible in the first place.
+	BlockFormat bool
+}
+
+// packJob is one file handed to a compression worker, tagged with its
+// position in the input stream so results can be put back in order.
+type packJob struct {
+	seq  int
+	file *File
+}
+
+// packResult is a completed compression job, ready to be written out once
+// every job before it (by seq) has been written.
+type packResult struct {
+	seq   int
+	entry indexEntry
+	block []byte
+	err   error
+}
+
+// PackStream compresses files read off a channel and streams the
+// resulting block-format bundle to out, writing each payload block as soon
+// as every block before it has been written. Files are fanned out across
+// Concurrency workers, each compressing one file at a time with a new
+// brotli.Writer per file, and written to out in the order they were
+// received, via a reorder buffer keyed by sequence number. That buffer is
+// not bounded: blocks finished ahead of a slow earlier file stay in memory
+// until that file is written.
+func PackStream(ctx context.Context, files <-chan *File, out io.Writer, opts PackOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	jobs := make(chan packJob)
+	results := make(chan packResult, concurrency)
+
+	var workers sync.WaitGroup
+	workers.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer workers.Done()
+			runPackWorker(jobs, results, opts.Quality)
+		}()
+	}
+
+	go feedPackJobs(ctx, files, jobs)
+
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	return reorderAndWrite(cancel, results, out, opts.Quality)
+}
+
+// feedPackJobs reads files off the input channel, tags each with its
+// sequence number, and forwards it to the worker pool, stopping early if
+// ctx is cancelled (e.g. a worker hit a compression error).
+func feedPackJobs(ctx context.Context, files <-chan *File, jobs chan<- packJob) {
+	defer close(jobs)
+
+	seq := 0
+	for {
+		select {
+		case f, ok := <-files:
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- packJob{seq: seq, file: f}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runPackWorker compresses jobs one at a time until jobs is closed; each job
+// gets a new brotli.Writer (see compressBlock), none is reused across jobs.
+func runPackWorker(jobs <-chan packJob, results chan<- packResult, quality int) {
+	for job := range jobs {
+		results <- compressPackJob(job, quality)
+	}
+}
+
+func compressPackJob(job packJob, quality int) packResult {
+	f := job.file
+	res := packResult{
+		seq: job.seq,
+		entry: indexEntry{
+			Path:    f.Fpath,
+			Mode:    f.Fmode,
+			ModTime: f.Modtime,
+			RawSize: int64(len(f.Data)),
+		},
+	}
+
+	if f.dir {
+		res.entry.Algo = algoStore
+		return res
+	}
+
+	block, err := compressBlock(f.Data, quality)
+	if err != nil {
+		res.err = err
+		return res
+	}
+
+	// Brotli occasionally expands already-dense input (small or
+	// high-entropy files); store it as-is rather than paying that cost on
+	// every future read.
+	if len(block) >= len(f.Data) {
+		res.block = f.Data
+		res.entry.Algo = algoStore
+		res.entry.CompressedSize = int64(len(f.Data))
+		return res
+	}
+
+	res.block = block
+	res.entry.Algo = algoBrotli
+	res.entry.CompressedSize = int64(len(block))
+	return res
+}
+
+// reorderAndWrite drains results, writing each payload block to out as
+// soon as every block before it (by seq) has already been written, then
+// appends the index footer once every result has been flushed.
+func reorderAndWrite(cancel context.CancelFunc, results <-chan packResult, out io.Writer, quality int) error {
+	if _, err := out.Write(formatMagic[:]); err != nil {
+		return err
+	}
+	if _, err := out.Write([]byte{formatVersionIndexLast}); err != nil {
+		return err
+	}
+
+	var (
+		pending  = map[int]packResult{}
+		next     int
+		offset   int64
+		entries  []indexEntry
+		firstErr error
+	)
+
+	flush := func(res packResult) {
+		if firstErr != nil {
+			return
+		}
+		if res.err != nil {
+			firstErr = res.err
+			cancel()
+			return
+		}
+
+		res.entry.Offset = offset
+		if len(res.block) > 0 {
+			if _, err := out.Write(res.block); err != nil {
+				firstErr = err
+				cancel()
+				return
+			}
+			offset += int64(len(res.block))
+		}
+
+		entries = append(entries, res.entry)
+	}
+
+	for res := range results {
+		pending[res.seq] = res
+		for {
+			cur, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			flush(cur)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	indexBytes, err := encodeIndexBytes(entries, quality)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(indexBytes); err != nil {
+		return err
+	}
+
+	var lenBuf [8]byte
+	binary.BigEndian.PutUint64(lenBuf[:], uint64(len(indexBytes)))
+	_, err = out.Write(lenBuf[:])
+	return err
+}
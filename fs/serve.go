@@ -0,0 +1,83 @@
+package fs
+
+import (
+	"io"
+	iofs "io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Serve returns an http.Handler that serves the virtual file tree rooted at
+// root, the same way http.FileServer(http.Dir(root)) would for a real
+// directory. Range, If-Range and conditional requests fall through to
+// net/http itself, via the fs.FS view returned by Broccoli.FS.
+//
+// Plain GET/HEAD requests that advertise "Accept-Encoding: br" are served
+// the file's still-compressed block directly, skipping a decompress/
+// recompress round trip. Range requests, and any request carrying a
+// conditional header (If-None-Match, If-Modified-Since, ...), always go
+// through the fallback path instead: byte ranges apply to the decoded
+// representation, and only the fallback path's http.ServeContent knows how
+// to evaluate conditionals into a 304/412.
+func (br *Broccoli) Serve(root string) http.Handler {
+	fsys := br.FS()
+
+	if root = clean(root); root != "" {
+		if sub, err := iofs.Sub(fsys, root); err == nil {
+			fsys = sub
+		}
+	}
+
+	fallback := http.FileServer(http.FS(fsys))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+			r.Header.Get("Range") == "" && acceptsBrotli(r) && !hasConditionalHeaders(r) {
+			name := path.Join(root, clean(r.URL.Path))
+
+			if rc, encoding, err := br.RawReader(name); err == nil {
+				defer rc.Close()
+
+				if encoding == "br" {
+					if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+						w.Header().Set("Content-Type", ctype)
+					}
+					w.Header().Set("Content-Encoding", "br")
+					w.Header().Set("Vary", "Accept-Encoding")
+					if r.Method == http.MethodGet {
+						io.Copy(w, rc)
+					}
+					return
+				}
+			}
+		}
+
+		fallback.ServeHTTP(w, r)
+	})
+}
+
+// acceptsBrotli reports whether r's Accept-Encoding header lists "br".
+func acceptsBrotli(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(enc) == "br" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasConditionalHeaders reports whether r carries any of the conditional
+// request headers net/http evaluates into a 304 (or 412). The brotli
+// passthrough in Serve doesn't set an ETag or Last-Modified, so it can't
+// evaluate these itself; requests that use them fall through to the
+// fallback http.FileServer path instead, which does.
+func hasConditionalHeaders(r *http.Request) bool {
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since"} {
+		if r.Header.Get(h) != "" {
+			return true
+		}
+	}
+	return false
+}
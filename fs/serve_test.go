@@ -0,0 +1,174 @@
+package fs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rangeTestData is an 11-byte fixture, long enough to exercise suffix,
+// open-ended and out-of-range requests without relying on disk fixtures.
+const rangeTestData = "0123456789A"
+
+func newRangeTestBroccoli() *Broccoli {
+	f := &File{
+		Fpath:   "file.txt",
+		Data:    []byte(rangeTestData),
+		Fmode:   0444,
+		Modtime: time.Unix(1000000000, 0),
+	}
+
+	br := &Broccoli{
+		filePaths: []string{f.Fpath},
+		files:     map[string]*File{f.Fpath: f},
+	}
+	f.br = br
+
+	return br
+}
+
+var ServeFileRangeTests = []struct {
+	r      string
+	code   int
+	ranges []string
+}{
+	{r: "", code: http.StatusOK},
+	{r: "bytes=0-4", code: http.StatusPartialContent, ranges: []string{"bytes 0-4/11"}},
+	{r: "bytes=2-", code: http.StatusPartialContent, ranges: []string{"bytes 2-10/11"}},
+	{r: "bytes=-5", code: http.StatusPartialContent, ranges: []string{"bytes 6-10/11"}},
+	{r: "bytes=0-0,-2", code: http.StatusPartialContent, ranges: []string{"bytes 0-0/11", "bytes 9-10/11"}},
+	{r: "bytes=5-1000", code: http.StatusPartialContent, ranges: []string{"bytes 5-10/11"}},
+	{r: "bytes=20-", code: http.StatusRequestedRangeNotSatisfiable},
+}
+
+func TestServeFileRange(t *testing.T) {
+	br := newRangeTestBroccoli()
+	srv := httptest.NewServer(br.Serve(""))
+	defer srv.Close()
+
+	for _, tt := range ServeFileRangeTests {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+		assert.NoError(t, err)
+		if tt.r != "" {
+			req.Header.Set("Range", tt.r)
+		}
+
+		resp, err := srv.Client().Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+
+		assert.Equalf(t, tt.code, resp.StatusCode, "Range: %q", tt.r)
+
+		switch tt.code {
+		case http.StatusRequestedRangeNotSatisfiable:
+			assert.Equal(t, "bytes */11", resp.Header.Get("Content-Range"))
+		case http.StatusPartialContent:
+			if len(tt.ranges) == 1 {
+				assert.Equal(t, tt.ranges[0], resp.Header.Get("Content-Range"))
+			} else {
+				ctype := resp.Header.Get("Content-Type")
+				assert.True(t, strings.HasPrefix(ctype, "multipart/byteranges; boundary="), ctype)
+				for _, cr := range tt.ranges {
+					assert.Contains(t, string(body), cr)
+				}
+			}
+		default:
+			assert.Equal(t, rangeTestData, string(body))
+		}
+	}
+}
+
+func TestServeFileIfRange(t *testing.T) {
+	br := newRangeTestBroccoli()
+	srv := httptest.NewServer(br.Serve(""))
+	defer srv.Close()
+
+	plain, err := srv.Client().Get(srv.URL + "/file.txt")
+	assert.NoError(t, err)
+	plain.Body.Close()
+	lastModified := plain.Header.Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	t.Run("matching If-Range serves 206", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		req.Header.Set("If-Range", lastModified)
+
+		resp, err := srv.Client().Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	})
+
+	t.Run("stale If-Range falls back to 200", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+		req.Header.Set("Range", "bytes=0-4")
+		req.Header.Set("If-Range", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+		resp, err := srv.Client().Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, rangeTestData, string(body))
+	})
+}
+
+func TestServeBrotliPassthroughSkipsConditionalRequests(t *testing.T) {
+	// The passthrough only engages for a brotli-compressed block, so pack a
+	// payload long enough for brotli to shrink; newRangeTestBroccoli's plain
+	// file would take the fallback path with or without conditional headers.
+	compressible := strings.Repeat(rangeTestData, 256)
+	bundle, err := Pack([]*File{{Fpath: "file.txt", Data: []byte(compressible), Fmode: 0444, Modtime: time.Unix(1000000000, 0)}}, 5)
+	assert.NoError(t, err)
+	br := New(DecompressLazy, bundle)
+
+	_, encoding, err := br.RawReader("file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "br", encoding)
+
+	srv := httptest.NewServer(br.Serve(""))
+	defer srv.Close()
+
+	plain, err := srv.Client().Get(srv.URL + "/file.txt")
+	assert.NoError(t, err)
+	plain.Body.Close()
+	lastModified := plain.Header.Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "br")
+	req.Header.Set("If-Modified-Since", lastModified)
+
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// A conditional GET must still be evaluated into a 304, even though it
+	// also asked for br: the passthrough path can't evaluate conditionals
+	// itself, so it must defer to the fallback path for this request.
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+}
+
+func TestServeFileNotFound(t *testing.T) {
+	br := newRangeTestBroccoli()
+	srv := httptest.NewServer(br.Serve(""))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/missing.txt")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
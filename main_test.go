@@ -18,7 +18,7 @@ import (
 
 var (
 	bundle, _ = defaultGenerator().generate()
-	br        = fs.New(false, bundle)
+	br        = fs.New(fs.DecompressEager, bundle)
 )
 
 func defaultGenerator() *Generator {
@@ -56,7 +56,7 @@ func TestBroccoli(t *testing.T) {
 	}
 	elapsed := time.Since(start)
 
-	br := fs.New(false, bundle)
+	br := fs.New(fs.DecompressEager, bundle)
 	br.Walk("./testdata", func(path string, _ os.FileInfo, _ error) error {
 		virtualPaths = append(virtualPaths, path)
 		return nil
@@ -73,7 +73,7 @@ func TestBroccoli(t *testing.T) {
 	assert.Equal(t, os.ErrNotExist, err)
 
 	assert.Panics(t, func() {
-		_ = fs.New(false, nil)
+		_ = fs.New(fs.DecompressEager, nil)
 	}, "New must panic with empty bundle")
 
 	err = br.Walk("testdata", func(path string, info os.FileInfo, err error) error {
@@ -81,7 +81,7 @@ func TestBroccoli(t *testing.T) {
 	})
 	assert.EqualError(t, err, "walk error")
 
-	br = fs.New(true, bundle)
+	br = fs.New(fs.DecompressLazy, bundle)
 	_, err = br.Open("testdata/index.html")
 	assert.NoError(t, err)
 }
@@ -93,7 +93,7 @@ func TestGenerate(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		br := fs.New(false, bundle)
+		br := fs.New(fs.DecompressEager, bundle)
 		br.Walk("testdata", walkFn)
 	}
 
@@ -300,7 +300,7 @@ func TestFileReaddir(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	br := fs.New(false, bundle)
+	br := fs.New(fs.DecompressEager, bundle)
 
 	dir, err := br.Open("testdata/readdir")
 	if err != nil {